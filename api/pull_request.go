@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PullRequest is a subset of a GitHub pull request's fields, enough to
+// drive status output like `gh pr view`/`gh pr checkout`.
+type PullRequest struct {
+	Number      int
+	State       string
+	Mergeable   string
+	HeadRefName string
+	BaseRefName string
+
+	StatusCheckRollup CheckRollup
+}
+
+// CheckRollup summarizes the combined state of a pull request's CI checks.
+type CheckRollup struct {
+	State string
+}
+
+type pullRequestNode struct {
+	Number      int    `json:"number"`
+	State       string `json:"state"`
+	Mergeable   string `json:"mergeable"`
+	HeadRefName string `json:"headRefName"`
+	BaseRefName string `json:"baseRefName"`
+	Commits     struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup struct {
+					State string `json:"state"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+}
+
+type pullRequestsForRef struct {
+	Nodes []pullRequestNode `json:"nodes"`
+}
+
+// graphQLDoer is the subset of *Client's behavior PullRequestForBranch
+// needs. It's expressed as an interface - rather than depending on *Client
+// directly - so tests can substitute a fake implementation instead of
+// making a real network request.
+type graphQLDoer interface {
+	GraphQL(query string, variables map[string]interface{}, data interface{}) error
+}
+
+// PullRequestForBranch looks up the open pull request, if any, whose
+// headRefName is branch across repos. It batches a single GraphQL query that
+// aliases one `repository(...)` field per repo instead of querying
+// repo-by-repo, then prefers a match found in a repo the viewer can push to,
+// falling back to the first repo (in input order) with a matching
+// headRefName. Only the branch name is compared: the owner of the pull
+// request's head repository is not checked.
+func PullRequestForBranch(client graphQLDoer, repos []*Repository, branch string) (*PullRequest, error) {
+	if len(repos) == 0 {
+		return nil, nil
+	}
+
+	query, err := buildPullRequestForBranchQuery(repos, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]pullRequestsForRef
+	if err := client.GraphQL(query, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed looking up pull requests: %w", err)
+	}
+
+	canPush := make([]bool, len(repos))
+	for i, repo := range repos {
+		canPush[i] = repo.ViewerCanPush()
+	}
+	return selectPullRequest(result, canPush, branch), nil
+}
+
+// selectPullRequest applies PullRequestForBranch's matching rule to an
+// already-fetched GraphQL result: prefer a match found in a repo the
+// viewer can push to, falling back to the first repo (in input order, by
+// alias index) with a matching headRefName. It's kept separate from
+// PullRequestForBranch so the selection rule itself can be unit tested
+// without a live GraphQL response.
+func selectPullRequest(result map[string]pullRequestsForRef, canPush []bool, branch string) *PullRequest {
+	var fallback *pullRequestNode
+	for i := range canPush {
+		res, ok := result[repoAlias(i)]
+		if !ok || len(res.Nodes) == 0 {
+			continue
+		}
+		node := res.Nodes[0]
+		if node.HeadRefName != branch {
+			continue
+		}
+		if canPush[i] {
+			return toPullRequest(node)
+		}
+		if fallback == nil {
+			fallback = &node
+		}
+	}
+
+	if fallback != nil {
+		return toPullRequest(*fallback)
+	}
+	return nil
+}
+
+func repoAlias(i int) string {
+	return fmt.Sprintf("repo%d", i)
+}
+
+func buildPullRequestForBranchQuery(repos []*Repository, branch string) (string, error) {
+	var b strings.Builder
+	b.WriteString("query PullRequestForBranch {\n")
+	for i, repo := range repos {
+		if repo == nil {
+			return "", fmt.Errorf("repos[%d] is nil", i)
+		}
+		fmt.Fprintf(&b, `  %s: repository(owner: %q, name: %q) {
+    pullRequests(headRefName: %q, states: OPEN, first: 1) {
+      nodes {
+        number
+        state
+        mergeable
+        headRefName
+        baseRefName
+        commits(last: 1) {
+          nodes {
+            commit {
+              statusCheckRollup { state }
+            }
+          }
+        }
+      }
+    }
+  }
+`, repoAlias(i), repo.RepoOwner(), repo.RepoName(), branch)
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+func toPullRequest(node pullRequestNode) *PullRequest {
+	pr := &PullRequest{
+		Number:      node.Number,
+		State:       node.State,
+		Mergeable:   node.Mergeable,
+		HeadRefName: node.HeadRefName,
+		BaseRefName: node.BaseRefName,
+	}
+	if len(node.Commits.Nodes) > 0 {
+		pr.StatusCheckRollup.State = node.Commits.Nodes[0].Commit.StatusCheckRollup.State
+	}
+	return pr
+}
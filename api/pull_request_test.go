@@ -0,0 +1,207 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+)
+
+func nodeWithHead(head string) pullRequestNode {
+	var n pullRequestNode
+	n.Number = 1
+	n.HeadRefName = head
+	return n
+}
+
+func TestRepoAlias(t *testing.T) {
+	cases := map[int]string{0: "repo0", 1: "repo1", 12: "repo12"}
+	for i, want := range cases {
+		if got := repoAlias(i); got != want {
+			t.Errorf("repoAlias(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestToPullRequest(t *testing.T) {
+	var node pullRequestNode
+	node.Number = 42
+	node.State = "OPEN"
+	node.Mergeable = "MERGEABLE"
+	node.HeadRefName = "feature"
+	node.BaseRefName = "main"
+	node.Commits.Nodes = []struct {
+		Commit struct {
+			StatusCheckRollup struct {
+				State string `json:"state"`
+			} `json:"statusCheckRollup"`
+		} `json:"commit"`
+	}{
+		{},
+	}
+	node.Commits.Nodes[0].Commit.StatusCheckRollup.State = "SUCCESS"
+
+	pr := toPullRequest(node)
+	if pr.Number != 42 || pr.State != "OPEN" || pr.Mergeable != "MERGEABLE" {
+		t.Errorf("unexpected pull request fields: %+v", pr)
+	}
+	if pr.HeadRefName != "feature" || pr.BaseRefName != "main" {
+		t.Errorf("unexpected branch names: %+v", pr)
+	}
+	if pr.StatusCheckRollup.State != "SUCCESS" {
+		t.Errorf("expected the rollup state from the last commit, got %q", pr.StatusCheckRollup.State)
+	}
+}
+
+func TestToPullRequest_NoCommits(t *testing.T) {
+	var node pullRequestNode
+	node.Number = 7
+
+	pr := toPullRequest(node)
+	if pr.StatusCheckRollup.State != "" {
+		t.Errorf("expected an empty rollup state when there are no commits, got %q", pr.StatusCheckRollup.State)
+	}
+}
+
+func TestPullRequestForBranch_NoRepos(t *testing.T) {
+	pr, err := PullRequestForBranch(nil, nil, "feature")
+	if err != nil {
+		t.Fatalf("expected no error for an empty repo list, got %v", err)
+	}
+	if pr != nil {
+		t.Errorf("expected a nil pull request for an empty repo list, got %+v", pr)
+	}
+}
+
+func TestSelectPullRequest_PrefersPushAccess(t *testing.T) {
+	pushable := nodeWithHead("feature")
+	pushable.Number = 2
+	result := map[string]pullRequestsForRef{
+		"repo0": {Nodes: []pullRequestNode{nodeWithHead("feature")}},
+		"repo1": {Nodes: []pullRequestNode{pushable}},
+	}
+	// repo0 can't be pushed to (e.g. it's the upstream), repo1 can (e.g.
+	// it's the user's fork) - the fork's PR should win even though it's
+	// not first in alias order.
+	pr := selectPullRequest(result, []bool{false, true}, "feature")
+	if pr == nil {
+		t.Fatal("expected a match")
+	}
+	if got := pr.HeadRefName; got != "feature" {
+		t.Errorf("got HeadRefName %q", got)
+	}
+	if got := pr.Number; got != 2 {
+		t.Errorf("expected repo1's PR #2, got #%d", got)
+	}
+}
+
+func TestSelectPullRequest_FallsBackWithoutPushAccess(t *testing.T) {
+	second := nodeWithHead("feature")
+	second.Number = 2
+	result := map[string]pullRequestsForRef{
+		"repo0": {Nodes: []pullRequestNode{nodeWithHead("feature")}},
+		"repo1": {Nodes: []pullRequestNode{second}},
+	}
+	// neither repo is pushable - the first repo (by alias index) with a
+	// matching headRefName should win.
+	pr := selectPullRequest(result, []bool{false, false}, "feature")
+	if pr == nil || pr.Number != 1 {
+		t.Fatalf("expected the first matching repo's PR, got %+v", pr)
+	}
+}
+
+func TestSelectPullRequest_SkipsNonMatchingHeadRef(t *testing.T) {
+	result := map[string]pullRequestsForRef{
+		"repo0": {Nodes: []pullRequestNode{nodeWithHead("other-branch")}},
+		"repo1": {Nodes: []pullRequestNode{nodeWithHead("feature")}},
+	}
+	pr := selectPullRequest(result, []bool{true, false}, "feature")
+	if pr == nil || pr.HeadRefName != "feature" {
+		t.Fatalf("expected to skip repo0's non-matching PR, got %+v", pr)
+	}
+}
+
+func TestSelectPullRequest_NoMatch(t *testing.T) {
+	result := map[string]pullRequestsForRef{
+		"repo0": {Nodes: []pullRequestNode{nodeWithHead("other-branch")}},
+	}
+	if pr := selectPullRequest(result, []bool{false}, "feature"); pr != nil {
+		t.Errorf("expected no match, got %+v", pr)
+	}
+}
+
+func TestSelectPullRequest_MissingOrEmptyAlias(t *testing.T) {
+	result := map[string]pullRequestsForRef{
+		"repo1": {Nodes: []pullRequestNode{nodeWithHead("feature")}},
+	}
+	// repo0 is missing from the result entirely, and repo2 resolved with
+	// no open PRs at all; neither should trip up the scan for repo1.
+	result["repo2"] = pullRequestsForRef{}
+	pr := selectPullRequest(result, []bool{false, false, false}, "feature")
+	if pr == nil || pr.HeadRefName != "feature" {
+		t.Fatalf("expected repo1's PR despite gaps in the result map, got %+v", pr)
+	}
+}
+
+// fakeGraphQLClient is a stand-in for *Client that records the query it was
+// given and returns a canned result, so PullRequestForBranch can be tested
+// without a real network call.
+type fakeGraphQLClient struct {
+	result map[string]pullRequestsForRef
+	err    error
+	query  string
+}
+
+func (f *fakeGraphQLClient) GraphQL(query string, variables map[string]interface{}, data interface{}) error {
+	f.query = query
+	if f.err != nil {
+		return f.err
+	}
+	out, ok := data.(*map[string]pullRequestsForRef)
+	if !ok {
+		return fmt.Errorf("unexpected data type %T", data)
+	}
+	*out = f.result
+	return nil
+}
+
+func TestPullRequestForBranch_FallsBackAcrossAliases(t *testing.T) {
+	client := &fakeGraphQLClient{
+		result: map[string]pullRequestsForRef{
+			"repo0": {Nodes: []pullRequestNode{nodeWithHead("other-branch")}},
+			"repo1": {Nodes: []pullRequestNode{nodeWithHead("feature")}},
+		},
+	}
+	repos := []*Repository{{}, {}}
+
+	pr, err := PullRequestForBranch(client, repos, "feature")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr == nil || pr.HeadRefName != "feature" {
+		t.Fatalf("expected to find repo1's matching PR, got %+v", pr)
+	}
+	if client.query == "" {
+		t.Error("expected PullRequestForBranch to send a query to the client")
+	}
+}
+
+func TestPullRequestForBranch_NoMatch(t *testing.T) {
+	client := &fakeGraphQLClient{result: map[string]pullRequestsForRef{}}
+	repos := []*Repository{{}}
+
+	pr, err := PullRequestForBranch(client, repos, "feature")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr != nil {
+		t.Errorf("expected no match, got %+v", pr)
+	}
+}
+
+func TestPullRequestForBranch_GraphQLError(t *testing.T) {
+	client := &fakeGraphQLClient{err: fmt.Errorf("boom")}
+	repos := []*Repository{{}}
+
+	if _, err := PullRequestForBranch(client, repos, "feature"); err == nil {
+		t.Fatal("expected the GraphQL error to propagate")
+	}
+}
@@ -0,0 +1,283 @@
+package context
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// oauthClientID is gh's registered OAuth application, used for the device
+// flow below. It has no client secret: device flow doesn't need one.
+const oauthClientID = "178c6fc778ccc68e1d6a"
+const oauthScopes = "repo,read:org"
+
+type deviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	Interval        int    `json:"interval"`
+}
+
+type accessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	Error       string `json:"error"`
+}
+
+// SetupAuth runs GitHub's OAuth device flow against defaultHostname,
+// persists the resulting token, and returns the authenticated login.
+func (c *fsContext) SetupAuth() (string, error) {
+	return c.setupAuthForHost(defaultHostname)
+}
+
+func (c *fsContext) setupAuthForHost(hostname string) (string, error) {
+	token, err := doDeviceFlow(hostname)
+	if err != nil {
+		// Older GitHub Enterprise releases don't support the device flow at
+		// all; fall back to hub's username/password(+OTP) flow rather than
+		// leaving the user with no way to authenticate.
+		token, err = promptAndAuthorizeWithOTP(hostname)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	c.SetAuthTokenForHost(hostname, token)
+
+	login, err := fetchAuthenticatedLogin(hostname, token)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := c.getConfig()
+	if err != nil {
+		return "", err
+	}
+	config.setHostConfig(hostname, login, token)
+	if err := writeConfigFile(configFile(), config); err != nil {
+		return "", err
+	}
+
+	return login, nil
+}
+
+// doDeviceFlow performs the device authorization grant: request a device
+// code, show the user a short code to enter at verification_uri, then poll
+// for the access token until the user completes the flow or it expires.
+func doDeviceFlow(hostname string) (string, error) {
+	dc, err := requestDeviceCode(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("First copy your one-time code: %s\n", dc.UserCode)
+	fmt.Printf("Then visit %s in your browser to continue...\n", dc.VerificationURI)
+
+	interval := time.Duration(dc.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		time.Sleep(interval)
+
+		token, err := pollForAccessToken(hostname, dc.DeviceCode)
+		switch {
+		case err == nil:
+			return token, nil
+		case err.Error() == "authorization_pending":
+			continue
+		case err.Error() == "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			return "", err
+		}
+	}
+}
+
+func requestDeviceCode(hostname string) (*deviceCodeResponse, error) {
+	form := url.Values{
+		"client_id": {oauthClientID},
+		"scope":     {oauthScopes},
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://%s/login/device/code", hostname), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("could not request a device code: %s", resp.Status)
+	}
+
+	var dc deviceCodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&dc); err != nil {
+		return nil, fmt.Errorf("could not parse device code response: %w", err)
+	}
+	if dc.DeviceCode == "" {
+		return nil, fmt.Errorf("device code response was missing a device_code")
+	}
+	return &dc, nil
+}
+
+func pollForAccessToken(hostname, deviceCode string) (string, error) {
+	form := url.Values{
+		"client_id":   {oauthClientID},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://%s/login/oauth/access_token", hostname), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("could not poll for an access token: %s", resp.Status)
+	}
+
+	var tr accessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", fmt.Errorf("could not parse access token response: %w", err)
+	}
+
+	switch tr.Error {
+	case "":
+		if tr.AccessToken == "" {
+			return "", fmt.Errorf("no access token in response")
+		}
+		return tr.AccessToken, nil
+	case "authorization_pending", "slow_down", "expired_token", "access_denied":
+		return "", fmt.Errorf(tr.Error)
+	default:
+		return "", fmt.Errorf("oauth error: %s", tr.Error)
+	}
+}
+
+// fetchAuthenticatedLogin fetches the login of the user the token belongs
+// to, to populate config.User.
+func fetchAuthenticatedLogin(hostname, token string) (string, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s/user", hostname), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "token "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("could not fetch the authenticated user: %s", resp.Status)
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", fmt.Errorf("could not parse user response: %w", err)
+	}
+	if user.Login == "" {
+		return "", fmt.Errorf("user response was missing a login")
+	}
+	return user.Login, nil
+}
+
+// promptAndAuthorizeWithOTP drives authorizeWithOTP interactively: it prompts
+// for a username and password, retrying with a one-time password if the
+// account turns out to have two-factor authentication enabled.
+func promptAndAuthorizeWithOTP(hostname string) (string, error) {
+	fmt.Print("Username: ")
+	var username string
+	if _, err := fmt.Scanln(&username); err != nil {
+		return "", fmt.Errorf("could not read username: %w", err)
+	}
+
+	fmt.Print("Password: ")
+	var password string
+	if _, err := fmt.Scanln(&password); err != nil {
+		return "", fmt.Errorf("could not read password: %w", err)
+	}
+
+	token, err := authorizeWithOTP(hostname, username, password, "")
+	if errors.Is(err, errNeedsOTP) {
+		fmt.Print("Two-factor authentication code: ")
+		var otp string
+		if _, err := fmt.Scanln(&otp); err != nil {
+			return "", fmt.Errorf("could not read authentication code: %w", err)
+		}
+		token, err = authorizeWithOTP(hostname, username, password, otp)
+	}
+	return token, err
+}
+
+// authorizeWithOTP is a fallback for hosts that haven't enabled the OAuth
+// device flow (older GitHub Enterprise releases): it authenticates with a
+// username and password, sending otp in the X-GitHub-OTP header when it is
+// non-empty. It does not retry by itself: when the API reports that the
+// account has two-factor authentication enabled and no otp was given, it
+// returns errNeedsOTP so the caller can call it again with one. This
+// mirrors hub's FindOrCreateToken behavior.
+func authorizeWithOTP(hostname, username, password, otp string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"scopes":      strings.Split(oauthScopes, ","),
+		"note":        "gh CLI",
+		"fingerprint": username + "@" + hostname,
+		"client_id":   oauthClientID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://%s/authorizations", hostname), strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Content-Type", "application/json")
+	if otp != "" {
+		req.Header.Set("X-GitHub-OTP", otp)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("X-GitHub-OTP") != "" && otp == "" {
+		return "", errNeedsOTP
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("authorization failed: %s", resp.Status)
+	}
+
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return "", fmt.Errorf("could not parse authorization response: %w", err)
+	}
+	return auth.Token, nil
+}
+
+// errNeedsOTP signals that the password-based flow needs a one-time
+// password before it can be retried.
+var errNeedsOTP = errors.New("this account requires a two-factor authentication code")
@@ -0,0 +1,142 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// withTestServer points http.DefaultClient at an httptest.Server for the
+// duration of fn, and passes fn the bare "host:port" this package's
+// hostname-based URL building expects.
+func withTestServer(t *testing.T, handler http.HandlerFunc, fn func(hostname string)) {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse test server URL: %v", err)
+	}
+
+	origClient := http.DefaultClient
+	http.DefaultClient = server.Client()
+	defer func() { http.DefaultClient = origClient }()
+
+	fn(u.Host)
+}
+
+func TestRequestDeviceCode_ErrorStatus(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}, func(hostname string) {
+		if _, err := requestDeviceCode(hostname); err == nil {
+			t.Fatal("expected an error for a 500 response, got nil")
+		}
+	})
+}
+
+func TestRequestDeviceCode_MissingDeviceCode(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"user_code":"ABCD-1234"}`)
+	}, func(hostname string) {
+		if _, err := requestDeviceCode(hostname); err == nil {
+			t.Fatal("expected an error when device_code is missing, got nil")
+		}
+	})
+}
+
+func TestRequestDeviceCode_Success(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"device_code":"dc123","user_code":"ABCD-1234","verification_uri":"https://example.com/device","interval":5}`)
+	}, func(hostname string) {
+		dc, err := requestDeviceCode(hostname)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dc.DeviceCode != "dc123" || dc.Interval != 5 {
+			t.Errorf("unexpected device code response: %+v", dc)
+		}
+	})
+}
+
+func TestPollForAccessToken_ErrorStatus(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}, func(hostname string) {
+		if _, err := pollForAccessToken(hostname, "dc123"); err == nil {
+			t.Fatal("expected an error for a 502 response, got nil")
+		}
+	})
+}
+
+func TestPollForAccessToken_AuthorizationPending(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":"authorization_pending"}`)
+	}, func(hostname string) {
+		_, err := pollForAccessToken(hostname, "dc123")
+		if err == nil || err.Error() != "authorization_pending" {
+			t.Fatalf("expected an authorization_pending error, got %v", err)
+		}
+	})
+}
+
+func TestPollForAccessToken_Success(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"tok123"}`)
+	}, func(hostname string) {
+		token, err := pollForAccessToken(hostname, "dc123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "tok123" {
+			t.Errorf("got token %q, want tok123", token)
+		}
+	})
+}
+
+func TestFetchAuthenticatedLogin_ErrorStatus(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}, func(hostname string) {
+		if _, err := fetchAuthenticatedLogin(hostname, "tok123"); err == nil {
+			t.Fatal("expected an error for a 401 response, got nil")
+		}
+	})
+}
+
+func TestFetchAuthenticatedLogin_MissingLogin(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}, func(hostname string) {
+		if _, err := fetchAuthenticatedLogin(hostname, "tok123"); err == nil {
+			t.Fatal("expected an error when login is missing, got nil")
+		}
+	})
+}
+
+func TestAuthorizeWithOTP_NeedsOTP(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-GitHub-OTP") == "" {
+			w.Header().Set("X-GitHub-OTP", "required; sms")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token":"tok123"}`)
+	}, func(hostname string) {
+		_, err := authorizeWithOTP(hostname, "monalisa", "hunter2", "")
+		if err != errNeedsOTP {
+			t.Fatalf("expected errNeedsOTP, got %v", err)
+		}
+
+		token, err := authorizeWithOTP(hostname, "monalisa", "hunter2", "123456")
+		if err != nil {
+			t.Fatalf("unexpected error retrying with an OTP: %v", err)
+		}
+		if token != "tok123" {
+			t.Errorf("got token %q, want tok123", token)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package context
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultHostname is used for any entry in config.yml that doesn't specify
+// a host, and is the host fsContext falls back to for the single-host
+// AuthToken/SetAuthToken/AuthLogin methods.
+const defaultHostname = "github.com"
+
+// configEntry is the parsed form of config.yml. Newer config files store a
+// list of per-host credentials under Hosts; User and Token are kept around
+// so that config.yml files written by older releases (a single, host-less
+// entry) still parse.
+type configEntry struct {
+	Hosts []hostConfig `yaml:"hosts,omitempty"`
+
+	// Deprecated: superseded by Hosts. Still read so existing config.yml
+	// files don't need to be migrated by hand.
+	User  string `yaml:"user,omitempty"`
+	Token string `yaml:"oauth_token,omitempty"`
+}
+
+// hostConfig holds the credentials for a single GitHub host, e.g.
+// "github.com" or a GitHub Enterprise hostname.
+type hostConfig struct {
+	Host  string `yaml:"host"`
+	User  string `yaml:"user"`
+	Token string `yaml:"oauth_token"`
+}
+
+// normalize folds the legacy top-level user/oauth_token fields into a
+// defaultHostname entry so the rest of the codebase only has to deal with
+// the Hosts list.
+func (c *configEntry) normalize() {
+	if c.User == "" && c.Token == "" {
+		return
+	}
+	if c.hostConfig(defaultHostname) == nil {
+		c.Hosts = append(c.Hosts, hostConfig{Host: defaultHostname, User: c.User, Token: c.Token})
+	}
+	c.User = ""
+	c.Token = ""
+}
+
+func (c *configEntry) hostConfig(hostname string) *hostConfig {
+	for i := range c.Hosts {
+		if c.Hosts[i].Host == hostname {
+			return &c.Hosts[i]
+		}
+	}
+	return nil
+}
+
+func (c *configEntry) setHostConfig(hostname, user, token string) {
+	if h := c.hostConfig(hostname); h != nil {
+		h.User = user
+		h.Token = token
+		return
+	}
+	c.Hosts = append(c.Hosts, hostConfig{Host: hostname, User: user, Token: token})
+}
+
+func parseOrSetupConfigFile(fn string) (*configEntry, error) {
+	config, err := parseConfigFile(fn)
+	if err != nil && os.IsNotExist(err) {
+		return setupConfigFile(fn)
+	}
+	return config, err
+}
+
+func parseConfigFile(fn string) (*configEntry, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data)
+}
+
+func parseConfig(data []byte) (*configEntry, error) {
+	var config configEntry
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	config.normalize()
+	return &config, nil
+}
+
+// setupConfigFile prompts for a personal access token for defaultHostname
+// and persists it. It is the fallback used the first time gh runs without a
+// config.yml. fsContext.SetupAuth runs the interactive OAuth device flow
+// instead; this package defines no `gh auth login` command.
+func setupConfigFile(fn string) (*configEntry, error) {
+	fmt.Print("Paste your GitHub personal access token: ")
+	var token string
+	if _, err := fmt.Scanln(&token); err != nil {
+		return nil, fmt.Errorf("could not read token: %w", err)
+	}
+
+	config := &configEntry{}
+	config.setHostConfig(defaultHostname, "", token)
+	if err := writeConfigFile(fn, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func writeConfigFile(fn string, config *configEntry) error {
+	if err := os.MkdirAll(filepath.Dir(fn), 0771); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+	return ioutil.WriteFile(fn, data, 0600)
+}
@@ -0,0 +1,77 @@
+package context
+
+import "testing"
+
+func TestConfigEntryNormalize_LegacySingleHost(t *testing.T) {
+	config, err := parseConfig([]byte("user: monalisa\noauth_token: abc123\n"))
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+
+	if config.User != "" || config.Token != "" {
+		t.Errorf("expected legacy fields to be cleared after normalize, got User=%q Token=%q", config.User, config.Token)
+	}
+
+	h := config.hostConfig(defaultHostname)
+	if h == nil {
+		t.Fatalf("expected a %s host entry after migrating the legacy format", defaultHostname)
+	}
+	if h.User != "monalisa" || h.Token != "abc123" {
+		t.Errorf("got user=%q token=%q, want user=monalisa token=abc123", h.User, h.Token)
+	}
+}
+
+func TestConfigEntryNormalize_MultiHostUnaffected(t *testing.T) {
+	data := []byte(`hosts:
+  - host: github.com
+    user: monalisa
+    oauth_token: abc123
+  - host: ghe.example.com
+    user: hubot
+    oauth_token: def456
+`)
+	config, err := parseConfig(data)
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+
+	if len(config.Hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(config.Hosts))
+	}
+
+	ghe := config.hostConfig("ghe.example.com")
+	if ghe == nil || ghe.Token != "def456" {
+		t.Errorf("expected ghe.example.com to keep its own token, got %+v", ghe)
+	}
+
+	github := config.hostConfig(defaultHostname)
+	if github == nil || github.Token != "abc123" {
+		t.Errorf("expected %s to keep its own token, got %+v", defaultHostname, github)
+	}
+}
+
+func TestConfigEntrySetHostConfig(t *testing.T) {
+	config := &configEntry{}
+	config.setHostConfig("ghe.example.com", "hubot", "tok1")
+	config.setHostConfig(defaultHostname, "monalisa", "tok2")
+
+	// updating an existing host should overwrite in place, not append
+	config.setHostConfig("ghe.example.com", "hubot", "tok3")
+
+	if len(config.Hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %d: %+v", len(config.Hosts), config.Hosts)
+	}
+	if h := config.hostConfig("ghe.example.com"); h == nil || h.Token != "tok3" {
+		t.Errorf("expected ghe.example.com token to be updated to tok3, got %+v", h)
+	}
+	if h := config.hostConfig(defaultHostname); h == nil || h.Token != "tok2" {
+		t.Errorf("expected %s token to remain tok2, got %+v", defaultHostname, h)
+	}
+}
+
+func TestConfigEntryHostConfig_Missing(t *testing.T) {
+	config := &configEntry{}
+	if h := config.hostConfig("nope.example.com"); h != nil {
+		t.Errorf("expected no host config for an unknown host, got %+v", h)
+	}
+}
@@ -1,10 +1,12 @@
 package context
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path"
 	"sort"
+	"strings"
 
 	"github.com/cli/cli/api"
 	"github.com/cli/cli/git"
@@ -12,11 +14,25 @@ import (
 	"github.com/mitchellh/go-homedir"
 )
 
+// Indirections over the git package's history-inspecting functions, so that
+// bestBaseRemote's disambiguation logic can be exercised in tests without a
+// real git repository on disk.
+var (
+	gitRevParse     = git.RevParse
+	gitMergeBase    = git.MergeBase
+	gitRevListCount = git.RevListCount
+)
+
 // Context represents the interface for querying information about the current environment
 type Context interface {
 	AuthToken() (string, error)
 	SetAuthToken(string)
 	AuthLogin() (string, error)
+	AuthTokenForHost(host string) (string, error)
+	SetAuthTokenForHost(host, token string)
+	AuthLoginForHost(host string) (string, error)
+	SetupAuth() (string, error)
+	Fetch(ctx context.Context, remotes []*Remote, refspecs []string) error
 	Branch() (string, error)
 	SetBranch(string)
 	Remotes() (Remotes, error)
@@ -28,7 +44,14 @@ type Context interface {
 // unusally large number of git remotes
 const maxRemotesForLookup = 5
 
-func ResolveRemotesToRepos(remotes Remotes, client *api.Client, base string) (ResolvedRemotes, error) {
+// ResolveRemotesToRepos looks up repository metadata for remotes, dispatching
+// each remote to the HostingService responsible for its host so that a mix
+// of github.com and e.g. a GitHub Enterprise remote resolves correctly
+// instead of one host's failure sinking the whole lookup. Each host group is
+// resolved using the credentials ctx has stored for that specific host
+// (see Context.AuthTokenForHost), so a GHE remote is never looked up using
+// the github.com token or vice versa.
+func ResolveRemotesToRepos(ctx Context, remotes Remotes, base string) (ResolvedRemotes, error) {
 	sort.Stable(remotes)
 	lenRemotesForLookup := len(remotes)
 	if lenRemotesForLookup > maxRemotesForLookup {
@@ -38,9 +61,17 @@ func ResolveRemotesToRepos(remotes Remotes, client *api.Client, base string) (Re
 	hasBaseOverride := base != ""
 	baseOverride := ghrepo.FromFullName(base)
 	foundBaseOverride := false
+
+	// repos and hosts are built in the same order BaseRepo/RemoteForRepo
+	// expect result.network.Repositories to end up in: one entry per looked-up
+	// remote, in remotes order, with any base override appended last.
 	repos := []ghrepo.Interface{}
+	hosts := []string{}
+	services := []HostingService{}
 	for _, r := range remotes[:lenRemotesForLookup] {
 		repos = append(repos, r)
+		hosts = append(hosts, r.RepoHost())
+		services = append(services, r.Service)
 		if ghrepo.IsSame(r, baseOverride) {
 			foundBaseOverride = true
 		}
@@ -49,28 +80,124 @@ func ResolveRemotesToRepos(remotes Remotes, client *api.Client, base string) (Re
 		// additionally, look up the explicitly specified base repo if it's not
 		// already covered by git remotes
 		repos = append(repos, baseOverride)
+		hosts = append(hosts, baseOverride.RepoHost())
+		services = append(services, nil)
 	}
 
-	result := ResolvedRemotes{remotes: remotes}
+	result := ResolvedRemotes{remotes: remotes, ctx: ctx, clients: map[string]*api.Client{}}
 	if hasBaseOverride {
 		result.baseOverride = baseOverride
 	}
-	networkResult, err := api.RepoNetwork(client, repos)
-	if err != nil {
-		return result, err
+
+	// group repo indices by host so a mix of github.com and a self-hosted
+	// forge - each with its own credentials - doesn't fail resolution for
+	// either, then stitch the per-host results back together in the
+	// original order.
+	indicesByHost := map[string][]int{}
+	var hostOrder []string
+	for i, host := range hosts {
+		if _, ok := indicesByHost[host]; !ok {
+			hostOrder = append(hostOrder, host)
+		}
+		indicesByHost[host] = append(indicesByHost[host], i)
+	}
+
+	resolved := make([]*api.Repository, len(repos))
+	var hostErrs hostResolveErrors
+	for _, host := range hostOrder {
+		indices := indicesByHost[host]
+		group := make([]ghrepo.Interface, len(indices))
+		for j, i := range indices {
+			group[j] = repos[i]
+		}
+
+		// use the service translateRemotes tagged the group's first remote
+		// with, falling back to the host registry for untagged entries
+		service := services[indices[0]]
+		if service == nil {
+			service = hostingServiceForHost(host)
+		}
+		if _, ok := service.(*githubService); ok {
+			// Only github.com/GHE hosts need an api.Client; other
+			// HostingService implementations bring their own credentials
+			// and don't touch ctx.AuthTokenForHost at all.
+			client, err := apiClientForHost(ctx, host)
+			if err != nil {
+				hostErrs = append(hostErrs, &hostResolveError{Host: host, Err: err})
+				continue
+			}
+			result.clients[host] = client
+			service = &githubService{client: client}
+		}
+
+		networkResult, err := service.ResolveRepos(group)
+		if err != nil {
+			hostErrs = append(hostErrs, &hostResolveError{Host: host, Err: err})
+			continue
+		}
+		for j, i := range indices {
+			if j < len(networkResult.Repositories) {
+				resolved[i] = networkResult.Repositories[j]
+			}
+		}
+	}
+	result.network = api.RepoNetworkResult{Repositories: resolved}
+	if len(hostErrs) > 0 {
+		return result, hostErrs
 	}
-	result.network = networkResult
 	return result, nil
 }
 
+// hostResolveError records a single host group's resolution failure so
+// ResolveRemotesToRepos can report every failing host instead of letting one
+// host's missing credentials or API error sink the repos that already
+// resolved for every other host.
+type hostResolveError struct {
+	Host string
+	Err  error
+}
+
+func (e *hostResolveError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Host, e.Err)
+}
+
+// hostResolveErrors aggregates the per-host failures from ResolveRemotesToRepos.
+type hostResolveErrors []*hostResolveError
+
+func (e hostResolveErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, he := range e {
+		msgs[i] = he.Error()
+	}
+	return fmt.Sprintf("failed to resolve %d host(s):\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// apiClientForHost builds an API client authenticated with the token ctx
+// has stored for hostname, so each host group in ResolveRemotesToRepos talks
+// to the API using its own credentials rather than a single shared client.
+func apiClientForHost(ctx Context, hostname string) (*api.Client, error) {
+	token, err := ctx.AuthTokenForHost(hostname)
+	if err != nil {
+		return nil, fmt.Errorf("could not get credentials for %s: %w", hostname, err)
+	}
+	httpClient := api.NewHTTPClient(api.AddHeader("Authorization", fmt.Sprintf("token %s", token)))
+	return api.NewClient(httpClient), nil
+}
+
 type ResolvedRemotes struct {
 	baseOverride ghrepo.Interface
 	remotes      Remotes
 	network      api.RepoNetworkResult
+	ctx          Context
+	clients      map[string]*api.Client
 }
 
 // BaseRepo is the first found repository in the "upstream", "github", "origin"
-// git remote order, resolved to the parent repo if the git remote points to a fork
+// git remote order, resolved to the parent repo if the git remote points to a
+// fork. When the ResolvedRemotes was built from a *fsContext (see
+// ResolveRemotesToRepos), that order is itself refined using local git
+// history: the remote whose default branch is an ancestor of, or shares the
+// most recent merge-base with, HEAD is preferred over the name-based order.
 func (r ResolvedRemotes) BaseRepo() (*api.Repository, error) {
 	if r.baseOverride != nil {
 		for _, repo := range r.network.Repositories {
@@ -82,6 +209,21 @@ func (r ResolvedRemotes) BaseRepo() (*api.Repository, error) {
 			ghrepo.FullName(r.baseOverride))
 	}
 
+	if fsCtx, ok := r.ctx.(*fsContext); ok {
+		if best := fsCtx.bestBaseRemote(r.remotes); best != nil {
+			for i, remote := range r.remotes {
+				if remote != best || i >= len(r.network.Repositories) || r.network.Repositories[i] == nil {
+					continue
+				}
+				repo := r.network.Repositories[i]
+				if repo.IsFork() {
+					return repo.Parent, nil
+				}
+				return repo, nil
+			}
+		}
+	}
+
 	for _, repo := range r.network.Repositories {
 		if repo == nil {
 			continue
@@ -105,6 +247,68 @@ func (r ResolvedRemotes) HeadRepo() (*api.Repository, error) {
 	return nil, errors.New("none of the repositories have push access")
 }
 
+// PullRequestForBranch looks up the open pull request whose headRefName is
+// branch among the repositories this ResolvedRemotes knows about (every
+// resolved remote plus, for forks, their parents), preferring a PR found in
+// a repo the user can push to; the owner of the PR's head repository is not
+// checked, and an error is returned when no PR matches. Repos are
+// grouped by host and queried one host group at a time - api.GraphQL is
+// GitHub-specific, so a single combined query can't span e.g. a github.com
+// remote and a GitHub Enterprise remote at once - returning the first
+// match found, in the same order repos were resolved in.
+func (r ResolvedRemotes) PullRequestForBranch(branch string) (*api.PullRequest, error) {
+	var repos []*api.Repository
+	for _, repo := range r.network.Repositories {
+		if repo == nil {
+			continue
+		}
+		repos = append(repos, repo)
+		if repo.IsFork() && repo.Parent != nil {
+			repos = append(repos, repo.Parent)
+		}
+	}
+	if len(repos) == 0 {
+		return nil, errors.New("no repositories resolved to look up pull requests against")
+	}
+
+	reposByHost := map[string][]*api.Repository{}
+	var hostOrder []string
+	for _, repo := range repos {
+		host := repo.RepoHost()
+		if _, ok := reposByHost[host]; !ok {
+			hostOrder = append(hostOrder, host)
+		}
+		reposByHost[host] = append(reposByHost[host], repo)
+	}
+
+	for _, host := range hostOrder {
+		client := r.clients[host]
+		if client == nil {
+			// No api.Client for this host - e.g. it's served by a non-GitHub
+			// HostingService, which doesn't speak GitHub's GraphQL API.
+			continue
+		}
+		pr, err := api.PullRequestForBranch(client, reposByHost[host], branch)
+		if err != nil {
+			return nil, err
+		}
+		if pr != nil {
+			return pr, nil
+		}
+	}
+	return nil, fmt.Errorf("no pull request found for branch %q", branch)
+}
+
+// PullRequestForCurrentBranch resolves ctx's current branch and looks up
+// the pull request associated with it among remotes.
+func PullRequestForCurrentBranch(ctx Context, remotes ResolvedRemotes) (*api.PullRequest, error) {
+	branch, err := ctx.Branch()
+	if err != nil {
+		return nil, err
+	}
+	return remotes.PullRequestForBranch(branch)
+}
+
 // RemoteForRepo finds the git remote that points to a repository
 func (r ResolvedRemotes) RemoteForRepo(repo ghrepo.Interface) (*Remote, error) {
 	for i, remote := range r.remotes {
@@ -125,11 +329,12 @@ func New() Context {
 
 // A Context implementation that queries the filesystem
 type fsContext struct {
-	config    *configEntry
-	remotes   Remotes
-	branch    string
-	baseRepo  ghrepo.Interface
-	authToken string
+	config         *configEntry
+	remotes        Remotes
+	branch         string
+	baseRepo       ghrepo.Interface
+	authTokens     map[string]string
+	mergeBaseCache map[string]string
 }
 
 func ConfigDir() string {
@@ -148,33 +353,59 @@ func (c *fsContext) getConfig() (*configEntry, error) {
 			return nil, err
 		}
 		c.config = entry
-		c.authToken = ""
+		c.authTokens = nil
 	}
 	return c.config, nil
 }
 
 func (c *fsContext) AuthToken() (string, error) {
-	if c.authToken != "" {
-		return c.authToken, nil
+	return c.AuthTokenForHost(defaultHostname)
+}
+
+func (c *fsContext) SetAuthToken(t string) {
+	c.SetAuthTokenForHost(defaultHostname, t)
+}
+
+func (c *fsContext) AuthLogin() (string, error) {
+	return c.AuthLoginForHost(defaultHostname)
+}
+
+// AuthTokenForHost returns the OAuth token stored for the given GitHub (or
+// GitHub Enterprise) hostname.
+func (c *fsContext) AuthTokenForHost(host string) (string, error) {
+	if t, ok := c.authTokens[host]; ok && t != "" {
+		return t, nil
 	}
 
 	config, err := c.getConfig()
 	if err != nil {
 		return "", err
 	}
-	return config.Token, nil
+	if h := config.hostConfig(host); h != nil {
+		return h.Token, nil
+	}
+	return "", fmt.Errorf("no stored credentials for %s", host)
 }
 
-func (c *fsContext) SetAuthToken(t string) {
-	c.authToken = t
+// SetAuthTokenForHost overrides the in-memory OAuth token used for host,
+// without persisting it to config.yml.
+func (c *fsContext) SetAuthTokenForHost(host, token string) {
+	if c.authTokens == nil {
+		c.authTokens = map[string]string{}
+	}
+	c.authTokens[host] = token
 }
 
-func (c *fsContext) AuthLogin() (string, error) {
+// AuthLoginForHost returns the GitHub login stored for host.
+func (c *fsContext) AuthLoginForHost(host string) (string, error) {
 	config, err := c.getConfig()
 	if err != nil {
 		return "", err
 	}
-	return config.User, nil
+	if h := config.hostConfig(host); h != nil {
+		return h.User, nil
+	}
+	return "", fmt.Errorf("no stored credentials for %s", host)
 }
 
 func (c *fsContext) Branch() (string, error) {
@@ -216,15 +447,97 @@ func (c *fsContext) BaseRepo() (ghrepo.Interface, error) {
 	if err != nil {
 		return nil, err
 	}
-	rem, err := remotes.FindByName("upstream", "github", "origin", "*")
-	if err != nil {
-		return nil, err
+
+	rem := c.bestBaseRemote(remotes)
+	if rem == nil {
+		rem, err = remotes.FindByName("upstream", "github", "origin", "*")
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	c.baseRepo = rem
 	return c.baseRepo, nil
 }
 
+// bestBaseRemote disambiguates between multiple viable remotes using local
+// git history: the remote whose default branch is an ancestor of HEAD wins
+// outright; among remotes that are merely related to HEAD (not an ancestor),
+// the one whose merge-base is fewest commits behind HEAD - i.e. the most
+// recent shared history - wins. It returns nil when the required refs
+// haven't been fetched locally, so callers fall back to the
+// "upstream","github","origin","*" name order instead.
+func (c *fsContext) bestBaseRemote(remotes Remotes) *Remote {
+	headSHA, err := gitRevParse("HEAD")
+	if err != nil {
+		return nil
+	}
+
+	var best *Remote
+	var bestIsAncestor bool
+	var bestDistance int
+	for _, remote := range remotes {
+		tipSHA, err := gitRevParse(remote.Name + "/HEAD")
+		if err != nil {
+			continue
+		}
+
+		mergeBaseSHA, ok := c.mergeBase(headSHA, tipSHA)
+		if !ok {
+			continue
+		}
+		isAncestor := mergeBaseSHA == tipSHA
+
+		if best == nil || (isAncestor && !bestIsAncestor) {
+			best, bestIsAncestor = remote, isAncestor
+			bestDistance = c.distanceFromHead(headSHA, mergeBaseSHA)
+			continue
+		}
+		if isAncestor == bestIsAncestor {
+			if distance := c.distanceFromHead(headSHA, mergeBaseSHA); distance < bestDistance {
+				best, bestDistance = remote, distance
+			}
+		}
+	}
+	return best
+}
+
+// distanceFromHead counts how many commits HEAD is ahead of mergeBaseSHA,
+// i.e. how stale that merge-base is relative to HEAD - the smaller, the more
+// recently the two histories diverged. Errors are treated as "very far",
+// which only deprioritizes the candidate rather than failing the lookup.
+func (c *fsContext) distanceFromHead(headSHA, mergeBaseSHA string) int {
+	if headSHA == mergeBaseSHA {
+		return 0
+	}
+	count, err := gitRevListCount(mergeBaseSHA, headSHA)
+	if err != nil {
+		return int(^uint(0) >> 1) // math.MaxInt
+	}
+	return count
+}
+
+// mergeBase returns the merge-base SHA of a and b, caching the result on c
+// since BaseRepo may consult it for every remote and `git merge-base`
+// requires walking history.
+func (c *fsContext) mergeBase(a, b string) (string, bool) {
+	key := a + ":" + b
+	if c.mergeBaseCache == nil {
+		c.mergeBaseCache = map[string]string{}
+	}
+	if sha, ok := c.mergeBaseCache[key]; ok {
+		return sha, sha != ""
+	}
+
+	sha, err := gitMergeBase(a, b)
+	if err != nil {
+		c.mergeBaseCache[key] = ""
+		return "", false
+	}
+	c.mergeBaseCache[key] = sha
+	return sha, true
+}
+
 func (c *fsContext) SetBaseRepo(nwo string) {
 	c.baseRepo = ghrepo.FromFullName(nwo)
 }
\ No newline at end of file
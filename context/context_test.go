@@ -0,0 +1,129 @@
+package context
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cli/cli/git"
+)
+
+// stubGit swaps the git.* indirections used by bestBaseRemote for the
+// duration of a test and restores them afterwards.
+func stubGit(t *testing.T, revParse func(string) (string, error), mergeBase func(string, string) (string, error), revListCount func(string, string) (int, error)) {
+	t.Helper()
+	origRevParse, origMergeBase, origRevListCount := gitRevParse, gitMergeBase, gitRevListCount
+	gitRevParse, gitMergeBase, gitRevListCount = revParse, mergeBase, revListCount
+	t.Cleanup(func() {
+		gitRevParse, gitMergeBase, gitRevListCount = origRevParse, origMergeBase, origRevListCount
+	})
+}
+
+func TestBestBaseRemote_PrefersAncestor(t *testing.T) {
+	stubGit(t,
+		func(ref string) (string, error) {
+			if ref == "HEAD" {
+				return "head-sha", nil
+			}
+			return ref + "-sha", nil
+		},
+		func(a, b string) (string, error) {
+			// "origin/HEAD-sha" is an ancestor of HEAD; "upstream/HEAD-sha" only
+			// shares unrelated history further back.
+			if b == "origin/HEAD-sha" {
+				return "origin/HEAD-sha", nil
+			}
+			return "old-common-sha", nil
+		},
+		func(from, to string) (int, error) { return 10, nil },
+	)
+
+	remotes := Remotes{
+		{Remote: &git.Remote{Name: "upstream"}},
+		{Remote: &git.Remote{Name: "origin"}},
+	}
+
+	c := &fsContext{}
+	best := c.bestBaseRemote(remotes)
+	if best == nil || best.Name != "origin" {
+		t.Fatalf("expected the ancestor remote 'origin' to win, got %+v", best)
+	}
+}
+
+func TestBestBaseRemote_PrefersMostRecentMergeBase(t *testing.T) {
+	stubGit(t,
+		func(ref string) (string, error) {
+			if ref == "HEAD" {
+				return "head-sha", nil
+			}
+			return ref + "-sha", nil
+		},
+		func(a, b string) (string, error) {
+			// Neither remote's tip is an ancestor of HEAD; both merge-base
+			// somewhere further back in history.
+			if b == "upstream/HEAD-sha" {
+				return "mb-upstream", nil
+			}
+			return "mb-origin", nil
+		},
+		func(from, to string) (int, error) {
+			if from == "mb-upstream" {
+				return 20, nil
+			}
+			return 5, nil
+		},
+	)
+
+	remotes := Remotes{
+		{Remote: &git.Remote{Name: "upstream"}},
+		{Remote: &git.Remote{Name: "origin"}},
+	}
+
+	c := &fsContext{}
+	best := c.bestBaseRemote(remotes)
+	if best == nil || best.Name != "origin" {
+		t.Fatalf("expected 'origin', whose merge-base is fewer commits behind HEAD, got %+v", best)
+	}
+}
+
+func TestBestBaseRemote_NoHeadRef(t *testing.T) {
+	stubGit(t,
+		func(ref string) (string, error) { return "", errors.New("not a git repository") },
+		func(a, b string) (string, error) { return "", nil },
+		func(from, to string) (int, error) { return 0, nil },
+	)
+
+	c := &fsContext{}
+	if best := c.bestBaseRemote(Remotes{{Remote: &git.Remote{Name: "origin"}}}); best != nil {
+		t.Errorf("expected nil when HEAD can't be resolved, got %+v", best)
+	}
+}
+
+func TestDistanceFromHead_SameSHA(t *testing.T) {
+	c := &fsContext{}
+	if d := c.distanceFromHead("abc", "abc"); d != 0 {
+		t.Errorf("expected 0 for identical SHAs, got %d", d)
+	}
+}
+
+func TestMergeBase_Caching(t *testing.T) {
+	calls := 0
+	stubGit(t,
+		func(ref string) (string, error) { return ref, nil },
+		func(a, b string) (string, error) {
+			calls++
+			return "merge-base-sha", nil
+		},
+		func(from, to string) (int, error) { return 0, nil },
+	)
+
+	c := &fsContext{}
+	sha1, ok1 := c.mergeBase("a", "b")
+	sha2, ok2 := c.mergeBase("a", "b")
+
+	if !ok1 || !ok2 || sha1 != "merge-base-sha" || sha2 != "merge-base-sha" {
+		t.Fatalf("unexpected merge-base results: %q %v / %q %v", sha1, ok1, sha2, ok2)
+	}
+	if calls != 1 {
+		t.Errorf("expected gitMergeBase to be called once and cached, got %d calls", calls)
+	}
+}
@@ -0,0 +1,121 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cli/cli/git"
+)
+
+// gitFetch indirects git.Fetch so fetchRemotes' fan-out and error
+// aggregation can be exercised in tests without shelling out to git.
+var gitFetch = git.Fetch
+
+// maxConcurrentFetches bounds how many `git fetch` subprocesses FetchAll/
+// Fetch run at once. It's deliberately separate from maxRemotesForLookup,
+// which caps API lookups and has no bearing on how many local git
+// processes it's safe to run in parallel.
+const maxConcurrentFetches = 5
+
+// defaultRefspec is the refspec `git fetch` normally uses for a remote.
+func defaultRefspec(remoteName string) string {
+	return fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", remoteName)
+}
+
+// PullRequestRefspec is a refspec that fetches GitHub's PR head refs
+// directly into a remote-scoped namespace, e.g. for `gh pr checkout`.
+func PullRequestRefspec(remoteName string) string {
+	return fmt.Sprintf("+refs/pull/*/head:refs/remotes/%s/pr/*", remoteName)
+}
+
+// fetchError records a single remote's `git fetch` failure so FetchAll can
+// report all of them instead of stopping at the first one.
+type fetchError struct {
+	Remote string
+	Err    error
+}
+
+func (e *fetchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Remote, e.Err)
+}
+
+// fetchErrors aggregates the per-remote failures from a fan-out fetch.
+type fetchErrors []*fetchError
+
+func (e fetchErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("failed to fetch %d remote(s):\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// Fetch runs `git fetch` against remotes in parallel (bounded by
+// maxConcurrentFetches), using refspecs for every remote, and writes fetch
+// progress to stderr. Errors are aggregated per-remote rather than
+// short-circuiting the whole operation.
+func (c *fsContext) Fetch(ctx context.Context, remotes []*Remote, refspecs []string) error {
+	return fetchRemotes(ctx, remotes, func(*Remote) []string { return refspecs }, os.Stderr)
+}
+
+// FetchAll fetches every remote this ResolvedRemotes knows about, using
+// each remote's default refspec plus a PR-refs refspec, and writes fetch
+// progress to out as it goes.
+func (r ResolvedRemotes) FetchAll(ctx context.Context, out io.Writer) error {
+	return fetchRemotes(ctx, r.remotes, func(remote *Remote) []string {
+		return []string{defaultRefspec(remote.Name), PullRequestRefspec(remote.Name)}
+	}, out)
+}
+
+// fetchRemotes is the shared fan-out implementation behind Context.Fetch
+// and ResolvedRemotes.FetchAll: it runs `git fetch` for each remote
+// concurrently (bounded by maxConcurrentFetches), serializing writes to out
+// since multiple `git fetch` processes report progress at once, and
+// collects every remote's error instead of stopping at the first failure.
+func fetchRemotes(ctx context.Context, remotes []*Remote, refspecsFor func(*Remote) []string, out io.Writer) error {
+	sem := make(chan struct{}, maxConcurrentFetches)
+	var wg sync.WaitGroup
+	var writeMu sync.Mutex
+	syncOut := func(p []byte) (int, error) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return out.Write(p)
+	}
+
+	var errMu sync.Mutex
+	var errs fetchErrors
+
+	for _, remote := range remotes {
+		remote := remote
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := gitFetch(ctx, remote.Name, refspecsFor(remote), writerFunc(syncOut))
+			if err != nil {
+				errMu.Lock()
+				errs = append(errs, &fetchError{Remote: remote.Name, Err: err})
+				errMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// writerFunc adapts a write function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
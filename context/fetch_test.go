@@ -0,0 +1,120 @@
+package context
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cli/cli/git"
+)
+
+func TestDefaultRefspec(t *testing.T) {
+	if got, want := defaultRefspec("origin"), "+refs/heads/*:refs/remotes/origin/*"; got != want {
+		t.Errorf("defaultRefspec(origin) = %q, want %q", got, want)
+	}
+}
+
+func TestPullRequestRefspec(t *testing.T) {
+	if got, want := PullRequestRefspec("origin"), "+refs/pull/*/head:refs/remotes/origin/pr/*"; got != want {
+		t.Errorf("PullRequestRefspec(origin) = %q, want %q", got, want)
+	}
+}
+
+func TestFetchErrors_Error(t *testing.T) {
+	errs := fetchErrors{
+		{Remote: "origin", Err: fmt.Errorf("connection refused")},
+		{Remote: "upstream", Err: fmt.Errorf("authentication failed")},
+	}
+	msg := errs.Error()
+	want := "failed to fetch 2 remote(s):\norigin: connection refused\nupstream: authentication failed"
+	if msg != want {
+		t.Errorf("got:\n%s\nwant:\n%s", msg, want)
+	}
+}
+
+func TestFetchRemotes_PartialFailure(t *testing.T) {
+	orig := gitFetch
+	defer func() { gitFetch = orig }()
+
+	var calls int32
+	gitFetch = func(ctx context.Context, remoteName string, refspecs []string, out io.Writer) error {
+		atomic.AddInt32(&calls, 1)
+		if remoteName == "broken" {
+			return fmt.Errorf("fetch failed")
+		}
+		return nil
+	}
+
+	remotes := []*Remote{
+		{Remote: &git.Remote{Name: "origin"}},
+		{Remote: &git.Remote{Name: "broken"}},
+		{Remote: &git.Remote{Name: "upstream"}},
+	}
+
+	var out bytes.Buffer
+	err := fetchRemotes(context.Background(), remotes, func(*Remote) []string { return []string{"+refs/heads/*"} }, &out)
+	if err == nil {
+		t.Fatal("expected an aggregated error for the one failing remote, got nil")
+	}
+	errs, ok := err.(fetchErrors)
+	if !ok || len(errs) != 1 || errs[0].Remote != "broken" {
+		t.Fatalf("expected a fetchErrors with one entry for 'broken', got %#v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected gitFetch to be called once per remote, got %d calls", calls)
+	}
+}
+
+func TestFetchRemotes_AllSucceed(t *testing.T) {
+	orig := gitFetch
+	defer func() { gitFetch = orig }()
+	gitFetch = func(ctx context.Context, remoteName string, refspecs []string, out io.Writer) error {
+		return nil
+	}
+
+	remotes := []*Remote{
+		{Remote: &git.Remote{Name: "origin"}},
+		{Remote: &git.Remote{Name: "upstream"}},
+	}
+
+	var out bytes.Buffer
+	if err := fetchRemotes(context.Background(), remotes, func(*Remote) []string { return nil }, &out); err != nil {
+		t.Fatalf("expected no error when every fetch succeeds, got %v", err)
+	}
+}
+
+func TestFetchRemotes_SerializesWrites(t *testing.T) {
+	orig := gitFetch
+	defer func() { gitFetch = orig }()
+
+	// Every concurrent fetch writes a multi-byte chunk; if fetchRemotes
+	// didn't serialize writes to out, interleaved writes could corrupt the
+	// output. This only probabilistically catches a race, but race
+	// detection (go test -race) catches the unsynchronized write outright.
+	var remotes []*Remote
+	for i := 0; i < maxConcurrentFetches*2; i++ {
+		remotes = append(remotes, &Remote{Remote: &git.Remote{Name: fmt.Sprintf("r%d", i)}})
+	}
+
+	var wg sync.WaitGroup
+	gitFetch = func(ctx context.Context, remoteName string, refspecs []string, out io.Writer) error {
+		wg.Add(1)
+		defer wg.Done()
+		_, err := out.Write([]byte("progress-line\n"))
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := fetchRemotes(context.Background(), remotes, func(*Remote) []string { return nil }, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wg.Wait()
+
+	if got, want := out.Len(), len(remotes)*len("progress-line\n"); got != want {
+		t.Errorf("expected every remote's write to land intact, got %d bytes, want %d", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package context
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/cli/cli/api"
+	"github.com/cli/cli/internal/ghrepo"
+)
+
+// HostingService abstracts the operations context needs from whatever code
+// forge a remote points at, so that a mix of github.com, GitHub Enterprise,
+// and eventually other forges (GitLab, Bitbucket, Gitea, ...) can be
+// resolved without ResolveRemotesToRepos/BaseRepo hard-coding GitHub's API.
+type HostingService interface {
+	// Name identifies the service, e.g. "github".
+	Name() string
+	// MatchURL reports whether u points at a repository hosted by this
+	// service.
+	MatchURL(u *url.URL) bool
+	// ResolveRepos looks up metadata - including fork parent and viewer
+	// permissions - for the given repositories.
+	ResolveRepos(repos []ghrepo.Interface) (api.RepoNetworkResult, error)
+	// PullRequestURL returns the web URL for opening/viewing a pull
+	// request for branch against repo.
+	PullRequestURL(repo ghrepo.Interface, branch string) string
+}
+
+// hostingServices is the registry of known services, keyed by hostname.
+// Hosts not present here fall back to defaultHostingService.
+var hostingServices = map[string]HostingService{}
+
+// defaultHostingService is used for any hostname without a more specific
+// entry in hostingServices; it also backs github.com and GitHub Enterprise
+// hosts, since they speak the same API.
+var defaultHostingService HostingService = &githubService{}
+
+// registerHostingService adds (or replaces) the service used for hostname.
+func registerHostingService(hostname string, service HostingService) {
+	hostingServices[strings.ToLower(hostname)] = service
+}
+
+// hostingServiceForHost returns the HostingService registered for hostname,
+// falling back to defaultHostingService when hostname has no specific entry.
+func hostingServiceForHost(hostname string) HostingService {
+	if service, ok := hostingServices[strings.ToLower(hostname)]; ok {
+		return service
+	}
+	return defaultHostingService
+}
+
+// hostingServiceForURL returns the HostingService responsible for u, trying
+// the registry by hostname first, then falling back to whichever service
+// claims to MatchURL.
+func hostingServiceForURL(u *url.URL) HostingService {
+	if u == nil {
+		return defaultHostingService
+	}
+	if service, ok := hostingServices[strings.ToLower(u.Hostname())]; ok {
+		return service
+	}
+	for _, service := range hostingServices {
+		if service.MatchURL(u) {
+			return service
+		}
+	}
+	if defaultHostingService.MatchURL(u) {
+		return defaultHostingService
+	}
+	return defaultHostingService
+}
+
+// githubService is the HostingService implementation for github.com and
+// GitHub Enterprise hosts.
+type githubService struct {
+	client *api.Client
+}
+
+func (s *githubService) Name() string {
+	return "github"
+}
+
+func (s *githubService) MatchURL(u *url.URL) bool {
+	if u == nil {
+		return false
+	}
+	hostname := strings.ToLower(u.Hostname())
+	return hostname == "github.com" || strings.HasPrefix(hostname, "github.")
+}
+
+func (s *githubService) ResolveRepos(repos []ghrepo.Interface) (api.RepoNetworkResult, error) {
+	return api.RepoNetwork(s.client, repos)
+}
+
+func (s *githubService) PullRequestURL(repo ghrepo.Interface, branch string) string {
+	return "https://" + repo.RepoHost() + "/" + ghrepo.FullName(repo) + "/pull/new/" + branch
+}
+
+func init() {
+	registerHostingService("github.com", defaultHostingService)
+}
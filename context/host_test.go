@@ -0,0 +1,95 @@
+package context
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/cli/cli/api"
+	"github.com/cli/cli/git"
+	"github.com/cli/cli/internal/ghrepo"
+)
+
+type fakeHostingService struct {
+	name string
+}
+
+func (s *fakeHostingService) Name() string { return s.name }
+func (s *fakeHostingService) MatchURL(u *url.URL) bool {
+	return u != nil && u.Hostname() == "gitlab.example.com"
+}
+func (s *fakeHostingService) ResolveRepos(repos []ghrepo.Interface) (api.RepoNetworkResult, error) {
+	return api.RepoNetworkResult{}, nil
+}
+func (s *fakeHostingService) PullRequestURL(repo ghrepo.Interface, branch string) string {
+	return "https://gitlab.example.com/" + ghrepo.FullName(repo) + "/-/merge_requests/new"
+}
+
+func TestHostingServiceForHost(t *testing.T) {
+	fake := &fakeHostingService{name: "gitlab"}
+	registerHostingService("gitlab.example.com", fake)
+	defer delete(hostingServices, "gitlab.example.com")
+
+	if got := hostingServiceForHost("GitLab.Example.com"); got != fake {
+		t.Errorf("expected the registered service regardless of hostname case, got %v", got)
+	}
+	if got := hostingServiceForHost("unregistered.example.com"); got != defaultHostingService {
+		t.Errorf("expected defaultHostingService for an unregistered host, got %v", got)
+	}
+}
+
+func TestHostingServiceForURL(t *testing.T) {
+	fake := &fakeHostingService{name: "gitlab"}
+	registerHostingService("gitlab.example.com", fake)
+	defer delete(hostingServices, "gitlab.example.com")
+
+	u, _ := url.Parse("https://gitlab.example.com/owner/repo")
+	if got := hostingServiceForURL(u); got != fake {
+		t.Errorf("expected the registered service for a matching hostname, got %v", got)
+	}
+
+	u, _ = url.Parse("https://github.com/owner/repo")
+	if got := hostingServiceForURL(u); got != defaultHostingService {
+		t.Errorf("expected defaultHostingService for github.com, got %v", got)
+	}
+}
+
+func TestGithubServiceMatchURL(t *testing.T) {
+	s := &githubService{}
+	cases := map[string]bool{
+		"https://github.com/owner/repo":  true,
+		"https://github.example.com/o/r": true,
+		"https://gitlab.example.com/o/r": false,
+	}
+	for raw, want := range cases {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("url.Parse(%q): %v", raw, err)
+		}
+		if got := s.MatchURL(u); got != want {
+			t.Errorf("MatchURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestRemotesFindByName(t *testing.T) {
+	upstream := &Remote{Remote: &git.Remote{Name: "upstream"}, Repo: mustParseRepo("upstream/cli")}
+	origin := &Remote{Remote: &git.Remote{Name: "origin"}, Repo: mustParseRepo("mona/cli")}
+	remotes := Remotes{origin, upstream}
+
+	if r, err := remotes.FindByName("upstream", "*"); err != nil || r != upstream {
+		t.Errorf("expected to find the upstream remote, got %v, %v", r, err)
+	}
+	if r, err := remotes.FindByName("nonexistent", "*"); err != nil || r != origin {
+		t.Errorf("expected the wildcard to fall back to the first remote, got %v, %v", r, err)
+	}
+	if _, err := (Remotes{}).FindByName("upstream"); err == nil {
+		t.Error("expected an error when no remotes match")
+	}
+}
+
+// mustParseRepo mirrors how ghrepo.FromFullName is called elsewhere in this
+// package (e.g. fsContext.SetBaseRepo): it's single-return, with no error to
+// check.
+func mustParseRepo(fullName string) ghrepo.Interface {
+	return ghrepo.FromFullName(fullName)
+}
@@ -0,0 +1,102 @@
+package context
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/cli/cli/git"
+	"github.com/cli/cli/internal/ghrepo"
+)
+
+// Remotes represents a set of git remotes resolved to GitHub (or other
+// forge) repositories.
+type Remotes []*Remote
+
+// remoteNameSortOrder is the default preference used to rank remotes when
+// local git history doesn't give a stronger signal (see
+// fsContext.bestBaseRemote).
+var remoteNameSortOrder = []string{"upstream", "github", "origin"}
+
+func remoteNameSortScore(name string) int {
+	for i, s := range remoteNameSortOrder {
+		if name == s {
+			return i
+		}
+	}
+	return len(remoteNameSortOrder)
+}
+
+func (r Remotes) Len() int      { return len(r) }
+func (r Remotes) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r Remotes) Less(i, j int) bool {
+	return remoteNameSortScore(r[i].Name) < remoteNameSortScore(r[j].Name)
+}
+
+// FindByName returns the first Remote whose name matches one of names, in
+// order; "*" matches any remote.
+func (r Remotes) FindByName(names ...string) (*Remote, error) {
+	for _, name := range names {
+		for _, remote := range r {
+			if remote.Name == name || name == "*" {
+				return remote, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no GitHub remotes found")
+}
+
+// Remote represents a git remote mapped to a hosted repository.
+type Remote struct {
+	*git.Remote
+	Repo ghrepo.Interface
+
+	// Service is the HostingService that resolved this remote's URL; it is
+	// what ResolveRemotesToRepos dispatches this remote's host group to.
+	Service HostingService
+}
+
+// RepoName returns the name of the remote's repository.
+func (r Remote) RepoName() string {
+	return r.Repo.RepoName()
+}
+
+// RepoOwner returns the name of the account that owns the remote's
+// repository.
+func (r Remote) RepoOwner() string {
+	return r.Repo.RepoOwner()
+}
+
+// RepoHost returns the hostname the remote's repository is hosted on.
+func (r Remote) RepoHost() string {
+	return r.Repo.RepoHost()
+}
+
+// translateRemotes converts git remotes into gh Remotes, resolving each
+// one's repository and tagging it with the HostingService responsible for
+// its host so that mixed-host checkouts can be dispatched per-service.
+func translateRemotes(gitRemotes git.RemoteSet, urlTranslate func(*url.URL) *url.URL) (remotes Remotes) {
+	for _, r := range gitRemotes {
+		var repoURL *url.URL
+		if r.FetchURL != nil {
+			repoURL = urlTranslate(r.FetchURL)
+		}
+		if repoURL == nil && r.PushURL != nil {
+			repoURL = urlTranslate(r.PushURL)
+		}
+		if repoURL == nil {
+			continue
+		}
+
+		repo, err := ghrepo.FromURL(repoURL)
+		if err != nil {
+			continue
+		}
+
+		remotes = append(remotes, &Remote{
+			Remote:  r,
+			Repo:    repo,
+			Service: hostingServiceForURL(repoURL),
+		})
+	}
+	return
+}
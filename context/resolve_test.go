@@ -0,0 +1,110 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/cli/cli/api"
+	"github.com/cli/cli/git"
+	"github.com/cli/cli/internal/ghrepo"
+)
+
+// testRepo is a minimal ghrepo.Interface implementation that doesn't depend
+// on ghrepo's own repo-parsing, so tests can construct repos on arbitrary
+// hosts (ghrepo.FromFullName always assumes github.com).
+type testRepo struct {
+	owner, name, host string
+}
+
+func (r testRepo) RepoOwner() string { return r.owner }
+func (r testRepo) RepoName() string  { return r.name }
+func (r testRepo) RepoHost() string  { return r.host }
+
+// countingHostingService resolves every repo it's given to a non-nil
+// *api.Repository and records how many times it was invoked, so tests can
+// assert that one host's failure doesn't prevent another host's group from
+// being resolved.
+type countingHostingService struct {
+	calls int
+	err   error
+}
+
+func (s *countingHostingService) Name() string            { return "counting" }
+func (s *countingHostingService) MatchURL(u *url.URL) bool { return false }
+func (s *countingHostingService) PullRequestURL(repo ghrepo.Interface, branch string) string {
+	return ""
+}
+func (s *countingHostingService) ResolveRepos(repos []ghrepo.Interface) (api.RepoNetworkResult, error) {
+	s.calls++
+	if s.err != nil {
+		return api.RepoNetworkResult{}, s.err
+	}
+	out := make([]*api.Repository, len(repos))
+	for i := range repos {
+		out[i] = &api.Repository{}
+	}
+	return api.RepoNetworkResult{Repositories: out}, nil
+}
+
+// noCredsContext is a Context whose AuthTokenForHost always fails, used to
+// simulate a host with no stored credentials.
+type noCredsContext struct{}
+
+func (noCredsContext) AuthToken() (string, error) { return "", fmt.Errorf("not implemented") }
+func (noCredsContext) SetAuthToken(string)         {}
+func (noCredsContext) AuthLogin() (string, error)  { return "", fmt.Errorf("not implemented") }
+func (noCredsContext) AuthTokenForHost(host string) (string, error) {
+	return "", fmt.Errorf("no stored credentials for %s", host)
+}
+func (noCredsContext) SetAuthTokenForHost(host, token string) {}
+func (noCredsContext) AuthLoginForHost(host string) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+func (noCredsContext) SetupAuth() (string, error) { return "", fmt.Errorf("not implemented") }
+func (noCredsContext) Fetch(ctx context.Context, remotes []*Remote, refspecs []string) error {
+	return nil
+}
+func (noCredsContext) Branch() (string, error)            { return "", fmt.Errorf("not implemented") }
+func (noCredsContext) SetBranch(string)                    {}
+func (noCredsContext) Remotes() (Remotes, error)           { return nil, fmt.Errorf("not implemented") }
+func (noCredsContext) BaseRepo() (ghrepo.Interface, error) { return nil, fmt.Errorf("not implemented") }
+func (noCredsContext) SetBaseRepo(string)                  {}
+
+func TestResolveRemotesToRepos_OneHostFailureDoesntSinkOthers(t *testing.T) {
+	fake := &countingHostingService{}
+	registerHostingService("gitlab.example.com", fake)
+	defer delete(hostingServices, "gitlab.example.com")
+
+	remotes := Remotes{
+		{Remote: &git.Remote{Name: "origin"}, Repo: testRepo{owner: "mona", name: "cli", host: "github.com"}},
+		{Remote: &git.Remote{Name: "gitlab"}, Repo: testRepo{owner: "mona", name: "cli", host: "gitlab.example.com"}},
+	}
+
+	result, err := ResolveRemotesToRepos(noCredsContext{}, remotes, "")
+
+	if err == nil {
+		t.Fatal("expected an error reporting the github.com host's missing credentials")
+	}
+	hostErrs, ok := err.(hostResolveErrors)
+	if !ok || len(hostErrs) != 1 || hostErrs[0].Host != "github.com" {
+		t.Fatalf("expected a hostResolveErrors with one entry for github.com, got %#v", err)
+	}
+
+	if fake.calls != 1 {
+		t.Errorf("expected the gitlab.example.com host to still be resolved, got %d calls", fake.calls)
+	}
+	if len(result.network.Repositories) != 2 || result.network.Repositories[1] == nil {
+		t.Fatalf("expected the gitlab.example.com repo to resolve despite github.com failing, got %+v", result.network.Repositories)
+	}
+	if result.network.Repositories[0] != nil {
+		t.Errorf("expected the github.com repo to be left unresolved, got %+v", result.network.Repositories[0])
+	}
+	if _, ok := result.clients["gitlab.example.com"]; ok {
+		t.Error("expected no api.Client to be built for a non-github HostingService")
+	}
+	if _, ok := result.clients["github.com"]; ok {
+		t.Error("expected no api.Client to be stored for a host whose credential lookup failed")
+	}
+}